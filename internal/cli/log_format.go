@@ -0,0 +1,30 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/windmilleng/tilt/internal/engine"
+)
+
+// logFormatFlag is shared by any command that spins up a
+// DockerComposeLogManager (e.g. `tilt up`), selecting how its docker-compose
+// output gets rendered.
+type logFormatFlag struct {
+	value string
+}
+
+func (f *logFormatFlag) addTo(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.value, "log-format", string(engine.LogFormatPlain),
+		"log output format: plain, pretty, or json")
+}
+
+func (f *logFormatFlag) logFormat() (engine.LogFormat, error) {
+	switch engine.LogFormat(f.value) {
+	case engine.LogFormatPlain, engine.LogFormatPretty, engine.LogFormatJSON:
+		return engine.LogFormat(f.value), nil
+	default:
+		return "", fmt.Errorf("invalid --log-format %q: must be one of plain, pretty, json", f.value)
+	}
+}
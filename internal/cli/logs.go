@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/windmilleng/tilt/internal/engine"
+	"github.com/windmilleng/tilt/internal/model"
+)
+
+type logsCmd struct {
+	since  string
+	tail   int
+	follow bool
+
+	logStore *engine.LogStore
+}
+
+// register builds the `tilt logs` subcommand, querying logStore (the same
+// LogStore instance DockerComposeLogManager appends to while Tilt is up).
+//
+// IMPORTANT: logStore only has the logs this process has collected itself.
+// There's no RPC/daemon channel yet for a separate `tilt logs` invocation to
+// reach a LogStore owned by a running `tilt up`, so outside of that process
+// this command only sees lines emitted after it starts -- it cannot replay
+// history from another session. Until that channel exists, `register` is
+// only useful wired into the same `tilt up` process (e.g. as an in-process
+// debug command), not shipped as a standalone subcommand.
+func (c *logsCmd) register(logStore *engine.LogStore) *cobra.Command {
+	c.logStore = logStore
+
+	cmd := &cobra.Command{
+		Use:   "logs <manifest>",
+		Short: "Print the logs for a docker-compose service collected by this process",
+		Long: "Print the logs for a docker-compose service collected by this process.\n\n" +
+			"This only works when run from within the same process as `tilt up` --\n" +
+			"there is no cross-process channel yet for reaching another session's\n" +
+			"logs, so invoking `tilt logs` as a separate command will not see any\n" +
+			"history from a `tilt up` running elsewhere.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.run(cmd.Context(), c.logStore, args)
+		},
+	}
+	cmd.Flags().StringVar(&c.since, "since", "", "only show logs at or after this RFC3339 timestamp")
+	cmd.Flags().IntVar(&c.tail, "tail", 0, "number of lines to show from the end of the log (0 means all)")
+	cmd.Flags().BoolVar(&c.follow, "follow", false, "keep streaming new lines as they're logged")
+	return cmd
+}
+
+func (c *logsCmd) run(ctx context.Context, logStore *engine.LogStore, args []string) error {
+	name := model.ManifestName(args[0])
+
+	var lines []engine.LogLine
+	if c.since != "" {
+		t, err := time.Parse(time.RFC3339, c.since)
+		if err != nil {
+			return fmt.Errorf("parsing --since: %v", err)
+		}
+		lines = logStore.Since(name, t)
+	} else {
+		lines = logStore.Tail(name, c.tail)
+	}
+
+	if len(lines) == 0 && !c.follow {
+		fmt.Fprintf(os.Stderr, "No logs found for %s in this process. Note: `tilt logs` only sees "+
+			"output collected by the process it's invoked from -- if you're expecting history from a "+
+			"`tilt up` running elsewhere, that isn't available yet.\n", name)
+	}
+
+	for _, l := range lines {
+		fmt.Printf("%s\n", l.Text)
+	}
+
+	if c.follow {
+		for l := range logStore.Follow(ctx, name) {
+			fmt.Printf("%s\n", l.Text)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package dockercompose
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// DockerComposeClient wraps the subset of `docker-compose` invocations Tilt
+// needs to manage the lifecycle of docker-compose-backed resources.
+//
+// Implementations shell out to the `docker-compose` binary (or an
+// equivalent) against the compose file at configPath, scoped to a single
+// service by name.
+type DockerComposeClient interface {
+	// Up brings a service up, optionally forcing a rebuild of its image
+	// first.
+	Up(ctx context.Context, configPath string, serviceName string, shouldBuild bool) error
+
+	// Restart restarts a service's running container in place, without
+	// rebuilding its image.
+	Restart(ctx context.Context, configPath string, serviceName string) error
+
+	// StreamLogs streams a service's log output starting at (but not
+	// including) the given timestamp, so a reconnecting watch can resume
+	// with `--since` instead of replaying everything it's already seen.
+	StreamLogs(ctx context.Context, configPath string, serviceName string, since time.Time) (io.ReadCloser, error)
+
+	// StreamTarToContainer copies the contents of the given tar stream into
+	// a service's running container, mirroring `docker cp`.
+	StreamTarToContainer(ctx context.Context, configPath string, serviceName string, tar io.Reader) error
+}
@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/windmilleng/tilt/internal/dockercompose"
@@ -15,13 +18,77 @@ import (
 // Collects logs from running docker-compose services.
 type DockerComposeLogManager struct {
 	watches map[model.ManifestName]dockerComposeLogWatch
-	dcc     dockercompose.DockerComposeClient
+
+	// Services whose logs we've given up on for this Tilt session (e.g.
+	// `logging.driver: none`), so we don't spawn a new watcher for them on
+	// every OnChange tick.
+	//
+	// Written from the per-watch goroutines spawned by OnChange and read from
+	// diff() (the OnChange/store dispatch goroutine), so it needs its own
+	// lock -- unlike m.watches, which is only ever touched from diff().
+	disabledMu       sync.Mutex
+	disabledServices map[model.ManifestName]bool
+
+	dcc      dockercompose.DockerComposeClient
+	logStore *LogStore
+	printer  LogPrinter
+
+	// logFilePath is the file the global log writer reopens on SIGHUP (e.g.
+	// so Tilt picks up the new inode after `logrotate` rotates it). Empty
+	// when Tilt is logging to its normal in-process sink, which doesn't need
+	// reopening.
+	logFilePath string
+
+	globalWriterOnce sync.Once
+	globalWriter     *ReopenableWriter
 }
 
-func NewDockerComposeLogManager(dcc dockercompose.DockerComposeClient) *DockerComposeLogManager {
+func NewDockerComposeLogManager(dcc dockercompose.DockerComposeClient, logStore *LogStore, logFormat LogFormat, logFilePath string) *DockerComposeLogManager {
 	return &DockerComposeLogManager{
-		watches: make(map[model.ManifestName]dockerComposeLogWatch),
-		dcc:     dcc,
+		watches:          make(map[model.ManifestName]dockerComposeLogWatch),
+		disabledServices: make(map[model.ManifestName]bool),
+		dcc:              dcc,
+		logStore:         logStore,
+		printer:          NewLogPrinter(logFormat),
+		logFilePath:      logFilePath,
+	}
+}
+
+// Reopen closes and reopens the destination the manager's global log writer
+// is currently writing to. Exposed so it can be triggered programmatically
+// (e.g. from a store action in tests) as well as via SIGHUP.
+func (m *DockerComposeLogManager) Reopen() error {
+	if m.globalWriter == nil {
+		return nil
+	}
+	return m.globalWriter.Reopen()
+}
+
+// getGlobalWriter lazily builds the ReopenableWriter wrapping the process's
+// global log sink. We only want one SIGHUP listener for the manager, no
+// matter how many per-service watches are running.
+func (m *DockerComposeLogManager) getGlobalWriter(ctx context.Context) (*ReopenableWriter, error) {
+	var err error
+	m.globalWriterOnce.Do(func() {
+		m.globalWriter, err = NewReopenableWriter(m.globalWriterOpener(ctx), false)
+	})
+	return m.globalWriter, err
+}
+
+// globalWriterOpener builds the WriterOpener the global ReopenableWriter
+// calls on SIGHUP. When Tilt is logging to a file, this has to actually
+// re-open that file by path -- re-fetching the logger's writer, as we did
+// before this fix, just hands back the same (possibly rotated-out) fd and
+// never recovers. When there's no log file configured, reopening is a no-op
+// by construction: there's no inode to go stale.
+func (m *DockerComposeLogManager) globalWriterOpener(ctx context.Context) WriterOpener {
+	if m.logFilePath == "" {
+		return func() (io.WriteCloser, error) {
+			return nopWriteCloser{logger.Get(ctx).Writer(logger.InfoLvl)}, nil
+		}
+	}
+	return func() (io.WriteCloser, error) {
+		return os.OpenFile(m.logFilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	}
 }
 
@@ -42,6 +109,10 @@ func (m *DockerComposeLogManager) diff(ctx context.Context, st store.RStore) (se
 		}
 		dcInfo := ms.Manifest.DCInfo()
 
+		if m.isDisabled(ms.Manifest.Name) {
+			continue
+		}
+
 		existing, isActive := m.watches[ms.Manifest.Name]
 		startWatchTime := time.Unix(0, 0)
 		if isActive {
@@ -53,6 +124,11 @@ func (m *DockerComposeLogManager) diff(ctx context.Context, st store.RStore) (se
 			// The active log watcher got cancelled somehow, so we need to create
 			// a new one that picks up where it left off.
 			startWatchTime = <-existing.terminationTime
+		} else if lastLogged := m.lastLoggedTime(ms.Manifest.Name); !lastLogged.IsZero() {
+			// We don't have an in-memory watch for this manifest (e.g. the
+			// manager itself just restarted), but the LogStore remembers
+			// where we left off.
+			startWatchTime = lastLogged
 		}
 
 		ctx, cancel := context.WithCancel(ctx)
@@ -80,6 +156,36 @@ func (m *DockerComposeLogManager) diff(ctx context.Context, st store.RStore) (se
 	return setup, teardown
 }
 
+// isDisabled reports whether we've given up on a service's logs for this
+// Tilt session.
+func (m *DockerComposeLogManager) isDisabled(name model.ManifestName) bool {
+	m.disabledMu.Lock()
+	defer m.disabledMu.Unlock()
+	return m.disabledServices[name]
+}
+
+// markDisabled records that we've given up on a service's logs for this
+// Tilt session. Safe to call from any of the manager's per-watch goroutines.
+func (m *DockerComposeLogManager) markDisabled(name model.ManifestName) {
+	m.disabledMu.Lock()
+	defer m.disabledMu.Unlock()
+	m.disabledServices[name] = true
+}
+
+// lastLoggedTime returns the timestamp of the most recent log line the
+// LogStore has on record for this manifest, or the zero Time if we have
+// none (i.e. there's nothing to resume from).
+func (m *DockerComposeLogManager) lastLoggedTime(name model.ManifestName) time.Time {
+	if m.logStore == nil {
+		return time.Time{}
+	}
+	tail := m.logStore.Tail(name, 1)
+	if len(tail) == 0 {
+		return time.Time{}
+	}
+	return tail[0].Time
+}
+
 func (m *DockerComposeLogManager) OnChange(ctx context.Context, st store.RStore) {
 	setup, teardown := m.diff(ctx, st)
 	for _, watch := range teardown {
@@ -92,13 +198,19 @@ func (m *DockerComposeLogManager) OnChange(ctx context.Context, st store.RStore)
 }
 
 func (m *DockerComposeLogManager) consumeLogs(watch dockerComposeLogWatch, st store.RStore) {
+	lastTimestamp := watch.startWatchTime
 	defer func() {
-		watch.terminationTime <- time.Now()
+		watch.terminationTime <- lastTimestamp
 	}()
 
 	name := watch.name
-	readCloser, err := m.dcc.StreamLogs(watch.ctx, watch.dcConfigPath, watch.name.String())
+	readCloser, err := m.dcc.StreamLogs(watch.ctx, watch.dcConfigPath, watch.name.String(), watch.startWatchTime)
 	if err != nil {
+		if isLogsNotSupportedErr(err) {
+			logger.Get(watch.ctx).Infof("Logs not supported for %s (is `logging.driver: none` configured?): %v", name, err)
+			m.markDisabled(name)
+			return
+		}
 		logger.Get(watch.ctx).Infof("Error streaming %s logs: %v", name, err)
 		return
 	}
@@ -106,16 +218,24 @@ func (m *DockerComposeLogManager) consumeLogs(watch dockerComposeLogWatch, st st
 		_ = readCloser.Close()
 	}()
 
-	// TODO(maia): docker-compose already prefixes logs, but maybe we want to roll
-	// our own (as in PodWatchManager) cuz it's prettier?
+	globalWriter, err := m.getGlobalWriter(watch.ctx)
+	if err != nil {
+		logger.Get(watch.ctx).Infof("Error opening global log writer for %s: %v", name, err)
+		return
+	}
+
 	globalLogWriter := DockerComposeGlobalLogWriter{
-		writer: logger.Get(watch.ctx).Writer(logger.InfoLvl),
+		writer:       globalWriter,
+		printer:      m.printer,
+		manifestName: name,
 	}
 	actionWriter := DockerComposeLogActionWriter{
 		store:        st,
 		manifestName: name,
+		logStore:     m.logStore,
 	}
-	multiWriter := io.MultiWriter(globalLogWriter, actionWriter)
+	timestampWriter := &dcLogTimestampWriter{lastTimestamp: &lastTimestamp}
+	multiWriter := io.MultiWriter(globalLogWriter, actionWriter, timestampWriter)
 
 	_, err = io.Copy(multiWriter, NewHardCancelReader(watch.ctx, readCloser))
 	if err != nil && watch.ctx.Err() == nil {
@@ -124,6 +244,54 @@ func (m *DockerComposeLogManager) consumeLogs(watch dockerComposeLogWatch, st st
 	}
 }
 
+// docker-compose returns an error along these lines when a service's logs
+// can't be read back (e.g. it's configured with `logging.driver: none`).
+// There's nothing we can do to recover from this, so rather than retrying
+// forever we disable the watch for the rest of this Tilt session.
+func isLogsNotSupportedErr(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "configured logging driver does not support reading") ||
+		strings.Contains(msg, "logs not supported")
+}
+
+// Tracks the timestamp of the last log line we've seen, so that a
+// reconnecting watch can resume with `--since` instead of replaying
+// everything (or dropping lines emitted while we were disconnected).
+type dcLogTimestampWriter struct {
+	lastTimestamp *time.Time
+}
+
+// Write may be called with more than one log line in a single chunk (or a
+// partial one), since its source is a streaming pipe, not a line-buffered
+// reader. Parse every line in the chunk and keep whichever is the last to
+// parse successfully, rather than just the first token of the whole chunk --
+// otherwise lastTimestamp can get stuck on an earlier line than what was
+// actually flushed, and a reconnect would resume with `--since` too early
+// and replay lines we've already shown.
+func (w *dcLogTimestampWriter) Write(p []byte) (n int, err error) {
+	for _, line := range bytes.Split(p, []byte("\n")) {
+		if ts, ok := parseDCLogTimestamp(line); ok {
+			*w.lastTimestamp = ts
+		}
+	}
+	return len(p), nil
+}
+
+// docker-compose log lines are prefixed with an RFC3339Nano timestamp when
+// streamed with `--timestamps`, e.g. "2019-01-02T15:04:05.999999999Z web_1 | ...".
+func parseDCLogTimestamp(p []byte) (time.Time, bool) {
+	line := string(p)
+	fields := strings.SplitN(line, " ", 2)
+	if len(fields) == 0 {
+		return time.Time{}, false
+	}
+	ts, err := time.Parse(time.RFC3339Nano, fields[0])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return ts, true
+}
+
 type dockerComposeLogWatch struct {
 	ctx             context.Context
 	cancel          func()
@@ -140,16 +308,40 @@ type dockerComposeLogWatch struct {
 type DockerComposeLogActionWriter struct {
 	store        store.RStore
 	manifestName model.ManifestName
+	logStore     *LogStore
 }
 
 func (w DockerComposeLogActionWriter) Write(p []byte) (n int, err error) {
 	if shouldFilterDCLog(p) {
 		return len(p), nil
 	}
+
+	line := append([]byte{}, p...)
 	w.store.Dispatch(DockerComposeLogAction{
 		ManifestName: w.manifestName,
-		Log:          append([]byte{}, p...),
+		Log:          line,
 	})
+
+	if w.logStore != nil {
+		// p may carry more than one line (see dcLogTimestampWriter), and the
+		// LogStore keeps one LogLine per line.
+		for _, l := range bytes.SplitAfter(line, []byte("\n")) {
+			if len(l) == 0 {
+				continue
+			}
+			ts, ok := parseDCLogTimestamp(l)
+			if !ok {
+				ts = time.Now()
+			}
+			w.logStore.Append(LogLine{
+				ManifestName: w.manifestName,
+				Time:         ts,
+				Stream:       "stdout",
+				Text:         l,
+			})
+		}
+	}
+
 	return len(p), nil
 }
 
@@ -164,7 +356,9 @@ func shouldFilterDCLog(p []byte) bool {
 }
 
 type DockerComposeGlobalLogWriter struct {
-	writer io.Writer
+	writer       io.Writer
+	printer      LogPrinter
+	manifestName model.ManifestName
 }
 
 func (w DockerComposeGlobalLogWriter) Write(p []byte) (n int, err error) {
@@ -172,5 +366,20 @@ func (w DockerComposeGlobalLogWriter) Write(p []byte) (n int, err error) {
 		return len(p), nil
 	}
 
-	return w.writer.Write(p)
+	printer := w.printer
+	if printer == nil {
+		printer = plainLogPrinter{}
+	}
+
+	// p may carry more than one line (see dcLogTimestampWriter), and a
+	// LogPrinter formats one line at a time.
+	for _, line := range bytes.SplitAfter(p, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if _, err = w.writer.Write(printer.Print(w.manifestName, "stdout", line)); err != nil {
+			return len(p), err
+		}
+	}
+	return len(p), nil
 }
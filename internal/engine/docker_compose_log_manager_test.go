@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseDCLogTimestamp(t *testing.T) {
+	line := "2019-01-02T15:04:05.999999999Z web_1 | listening on :8080"
+	ts, ok := parseDCLogTimestamp([]byte(line))
+	if !ok {
+		t.Fatalf("parseDCLogTimestamp(%q) = _, false, want true", line)
+	}
+	want := time.Date(2019, 1, 2, 15, 4, 5, 999999999, time.UTC)
+	if !ts.Equal(want) {
+		t.Errorf("parseDCLogTimestamp(%q) = %v, want %v", line, ts, want)
+	}
+}
+
+func TestParseDCLogTimestampNoTimestamp(t *testing.T) {
+	_, ok := parseDCLogTimestamp([]byte("listening on :8080"))
+	if ok {
+		t.Errorf("parseDCLogTimestamp on untimestamped line should fail")
+	}
+}
+
+func TestDCLogTimestampWriterTracksLastTimestamp(t *testing.T) {
+	var last time.Time
+	w := &dcLogTimestampWriter{lastTimestamp: &last}
+
+	_, _ = w.Write([]byte("2019-01-02T15:04:05Z web_1 | one"))
+	_, _ = w.Write([]byte("not a timestamped line"))
+	_, _ = w.Write([]byte("2019-01-02T15:04:06Z web_1 | two"))
+
+	want := time.Date(2019, 1, 2, 15, 4, 6, 0, time.UTC)
+	if !last.Equal(want) {
+		t.Errorf("lastTimestamp = %v, want %v", last, want)
+	}
+}
+
+func TestDCLogTimestampWriterHandlesMultipleLinesPerWrite(t *testing.T) {
+	var last time.Time
+	w := &dcLogTimestampWriter{lastTimestamp: &last}
+
+	// io.Copy's source is a streaming pipe, not a line-buffered reader, so a
+	// single Write can carry more than one log line.
+	chunk := "2019-01-02T15:04:05Z web_1 | one\n" +
+		"2019-01-02T15:04:06Z web_1 | two\n" +
+		"2019-01-02T15:04:07Z web_1 | three"
+	_, _ = w.Write([]byte(chunk))
+
+	want := time.Date(2019, 1, 2, 15, 4, 7, 0, time.UTC)
+	if !last.Equal(want) {
+		t.Errorf("lastTimestamp = %v, want the last line's timestamp %v (not the first line's)", last, want)
+	}
+}
+
+func TestIsLogsNotSupportedErr(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("the configured logging driver does not support reading"), true},
+		{errors.New("logs not supported for this service"), true},
+		{errors.New("connection refused"), false},
+	}
+	for _, c := range cases {
+		if got := isLogsNotSupportedErr(c.err); got != c.want {
+			t.Errorf("isLogsNotSupportedErr(%q) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
+
+func TestDisabledServicesConcurrentAccess(t *testing.T) {
+	m := NewDockerComposeLogManager(nil, nil, LogFormatPlain, "")
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 1000; i++ {
+			m.markDisabled("svc")
+		}
+		close(done)
+	}()
+	for i := 0; i < 1000; i++ {
+		m.isDisabled("svc")
+	}
+	<-done
+
+	if !m.isDisabled("svc") {
+		t.Errorf("expected svc to be marked disabled")
+	}
+}
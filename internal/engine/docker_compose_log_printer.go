@@ -0,0 +1,136 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/windmilleng/tilt/internal/model"
+)
+
+// LogFormat selects how DockerComposeLogManager renders a service's log
+// lines before they hit the global writer (stdout/log file).
+type LogFormat string
+
+const (
+	LogFormatPlain  LogFormat = "plain"
+	LogFormatPretty LogFormat = "pretty"
+	LogFormatJSON   LogFormat = "json"
+)
+
+// LogPrinter formats a single log line from a docker-compose service for
+// the global writer. Implementations must be safe for concurrent use, since
+// a printer is shared across all of a manager's per-service watches.
+type LogPrinter interface {
+	Print(name model.ManifestName, stream string, line []byte) []byte
+}
+
+// NewLogPrinter builds the LogPrinter for the given format, defaulting to
+// plain output for an unrecognized or empty format.
+func NewLogPrinter(format LogFormat) LogPrinter {
+	switch format {
+	case LogFormatPretty:
+		return newPrettyLogPrinter()
+	case LogFormatJSON:
+		return jsonLogPrinter{}
+	default:
+		return plainLogPrinter{}
+	}
+}
+
+// plainLogPrinter is today's behavior: pass docker-compose's own
+// (already-prefixed) output straight through.
+type plainLogPrinter struct{}
+
+func (plainLogPrinter) Print(name model.ManifestName, stream string, line []byte) []byte {
+	return line
+}
+
+// prettyLogPrinter mirrors `docker-compose`'s attach output: each service's
+// lines get a stable color and a "<manifest name> | " prefix (replacing
+// docker-compose's own "service_1 | " prefix), so interleaved
+// output from multiple services stays easy to scan.
+type prettyLogPrinter struct {
+	mu     sync.Mutex
+	colors map[model.ManifestName]int
+	next   int
+}
+
+// 10 terminal colors that read well on both light and dark backgrounds,
+// cycled through in assignment order (same approach compose v2 uses for its
+// attach output).
+var prettyLogColors = []int{2, 3, 4, 5, 6, 42, 130, 103, 129, 162}
+
+func newPrettyLogPrinter() *prettyLogPrinter {
+	return &prettyLogPrinter{colors: make(map[model.ManifestName]int)}
+}
+
+func (p *prettyLogPrinter) colorFor(name model.ManifestName) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c, ok := p.colors[name]
+	if !ok {
+		c = prettyLogColors[p.next%len(prettyLogColors)]
+		p.colors[name] = c
+		p.next++
+	}
+	return c
+}
+
+func (p *prettyLogPrinter) Print(name model.ManifestName, stream string, line []byte) []byte {
+	color := p.colorFor(name)
+	prefix := fmt.Sprintf("\033[1;38;5;%dm%s |\033[0m ", color, name)
+	return append([]byte(prefix), stripDCLinePrefix(line)...)
+}
+
+// jsonLogPrinter emits one JSON record per line, for machine consumption by
+// downstream tooling.
+type jsonLogPrinter struct{}
+
+type jsonLogRecord struct {
+	Time    time.Time `json:"ts"`
+	Service string    `json:"service"`
+	Stream  string    `json:"stream"`
+	Msg     string    `json:"msg"`
+}
+
+func (jsonLogPrinter) Print(name model.ManifestName, stream string, line []byte) []byte {
+	ts, ok := parseDCLogTimestamp(line)
+	if !ok {
+		ts = time.Now()
+	}
+
+	rec := jsonLogRecord{
+		Time:    ts,
+		Service: name.String(),
+		Stream:  stream,
+		Msg:     string(stripDCLinePrefix(line)),
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		// Should be unreachable -- jsonLogRecord only has marshalable fields.
+		return line
+	}
+	return append(b, '\n')
+}
+
+// stripDCLinePrefix strips docker-compose's own "<timestamp> <service> | "
+// prefix from a raw log line, if present, so a LogPrinter can lay down its
+// own timestamp/service formatting without doubling up on docker-compose's.
+// Each part of the prefix is stripped only if present, so a line with
+// neither a leading timestamp nor " | " comes back unchanged.
+func stripDCLinePrefix(line []byte) []byte {
+	rest := line
+	if _, ok := parseDCLogTimestamp(rest); ok {
+		if idx := bytes.IndexByte(rest, ' '); idx >= 0 {
+			rest = rest[idx+1:]
+		}
+	}
+	if idx := bytes.Index(rest, []byte(" | ")); idx >= 0 {
+		return rest[idx+3:]
+	}
+	return rest
+}
@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/windmilleng/tilt/internal/model"
+)
+
+func TestNewLogPrinterDefaultsToPlain(t *testing.T) {
+	cases := []LogFormat{LogFormatPlain, LogFormat("bogus"), LogFormat("")}
+	for _, f := range cases {
+		if _, ok := NewLogPrinter(f).(plainLogPrinter); !ok {
+			t.Errorf("NewLogPrinter(%q) = %T, want plainLogPrinter", f, NewLogPrinter(f))
+		}
+	}
+}
+
+func TestPlainLogPrinterPassesThrough(t *testing.T) {
+	p := plainLogPrinter{}
+	line := []byte("web_1 | listening on :8080")
+	got := p.Print("web", "stdout", line)
+	if string(got) != string(line) {
+		t.Errorf("plainLogPrinter.Print() = %q, want %q unchanged", got, line)
+	}
+}
+
+func TestPrettyLogPrinterStableColorPerService(t *testing.T) {
+	p := newPrettyLogPrinter()
+	line := []byte("listening on :8080")
+
+	first := p.Print("web", "stdout", line)
+	second := p.Print("web", "stdout", line)
+	if string(first) != string(second) {
+		t.Errorf("expected the same manifest to get a stable color/prefix, got %q then %q", first, second)
+	}
+
+	other := p.Print("worker", "stdout", line)
+	if string(other) == string(first) {
+		t.Errorf("expected different manifests to get different prefixes, got the same %q", other)
+	}
+}
+
+func TestPrettyLogPrinterStripsDockerComposePrefix(t *testing.T) {
+	p := newPrettyLogPrinter()
+	line := []byte("2019-01-02T15:04:05.999999999Z web_1 | listening on :8080")
+
+	got := string(p.Print("web", "stdout", line))
+	if strings.Contains(got, "web_1 |") {
+		t.Errorf("Print() = %q, want docker-compose's own \"web_1 |\" prefix stripped", got)
+	}
+	if !strings.HasSuffix(got, "listening on :8080") {
+		t.Errorf("Print() = %q, want it to end with the bare message", got)
+	}
+}
+
+func TestStripDCLinePrefix(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"2019-01-02T15:04:05.999999999Z web_1 | listening on :8080", "listening on :8080"},
+		{"web_1 | listening on :8080", "listening on :8080"},
+		{"no prefix here", "no prefix here"},
+	}
+	for _, c := range cases {
+		got := string(stripDCLinePrefix([]byte(c.in)))
+		if got != c.want {
+			t.Errorf("stripDCLinePrefix(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestJSONLogPrinterEmitsOneRecordPerLine(t *testing.T) {
+	p := jsonLogPrinter{}
+	got := p.Print(model.ManifestName("web"), "stderr", []byte("boom"))
+
+	if !strings.HasSuffix(string(got), "\n") {
+		t.Fatalf("jsonLogPrinter.Print() = %q, want trailing newline", got)
+	}
+
+	var rec jsonLogRecord
+	if err := json.Unmarshal(got[:len(got)-1], &rec); err != nil {
+		t.Fatalf("jsonLogPrinter.Print() produced invalid JSON: %v", err)
+	}
+	if rec.Service != "web" || rec.Stream != "stderr" || rec.Msg != "boom" {
+		t.Errorf("jsonLogPrinter.Print() = %+v, want service=web stream=stderr msg=boom", rec)
+	}
+}
+
+func TestJSONLogPrinterStripsPrefixAndUsesParsedTimestamp(t *testing.T) {
+	p := jsonLogPrinter{}
+	line := []byte("2019-01-02T15:04:05.999999999Z web_1 | boom")
+	got := p.Print(model.ManifestName("web"), "stderr", line)
+
+	var rec jsonLogRecord
+	if err := json.Unmarshal(got[:len(got)-1], &rec); err != nil {
+		t.Fatalf("jsonLogPrinter.Print() produced invalid JSON: %v", err)
+	}
+
+	if rec.Msg != "boom" {
+		t.Errorf("Msg = %q, want docker-compose's own prefix stripped down to %q", rec.Msg, "boom")
+	}
+
+	want := time.Date(2019, 1, 2, 15, 4, 5, 999999999, time.UTC)
+	if !rec.Time.Equal(want) {
+		t.Errorf("Time = %v, want the line's own parsed timestamp %v", rec.Time, want)
+	}
+}
@@ -0,0 +1,150 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/windmilleng/tilt/internal/model"
+)
+
+// How much log data we keep in memory per manifest before evicting the
+// oldest lines. Chosen to keep a generous scrollback without letting a
+// noisy, long-running service blow out Tilt's memory footprint.
+const defaultLogStoreByteCap = 5 * 1000 * 1000
+
+// A single line of docker-compose output, enough to replay it later or
+// render it in the HUD.
+type LogLine struct {
+	ManifestName model.ManifestName
+	Time         time.Time
+	Stream       string // "stdout" or "stderr"
+	Text         []byte
+}
+
+func (l LogLine) byteLen() int {
+	return len(l.Text)
+}
+
+// Stores docker-compose logs in memory, indexed by manifest, so that the
+// HUD and `tilt logs` can replay a service's recent output without
+// re-attaching to docker-compose.
+//
+// This is a plain ring buffer per manifest (evicted by byte count, not line
+// count), guarded by a single mutex -- logs don't come in fast enough for
+// that to be a bottleneck.
+type LogStore struct {
+	mu      sync.Mutex
+	byteCap int
+	logs    map[model.ManifestName]*manifestLog
+}
+
+func NewLogStore(byteCap int) *LogStore {
+	if byteCap <= 0 {
+		byteCap = defaultLogStoreByteCap
+	}
+	return &LogStore{
+		byteCap: byteCap,
+		logs:    make(map[model.ManifestName]*manifestLog),
+	}
+}
+
+type manifestLog struct {
+	lines     []LogLine
+	byteCount int
+	followers []chan LogLine
+}
+
+// Append a line to the given manifest's log, evicting old lines if we've
+// gone over the byte cap, and fanning the line out to any active
+// `Follow` subscribers.
+func (s *LogStore) Append(line LogLine) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ml, ok := s.logs[line.ManifestName]
+	if !ok {
+		ml = &manifestLog{}
+		s.logs[line.ManifestName] = ml
+	}
+
+	ml.lines = append(ml.lines, line)
+	ml.byteCount += line.byteLen()
+	for ml.byteCount > s.byteCap && len(ml.lines) > 0 {
+		ml.byteCount -= ml.lines[0].byteLen()
+		ml.lines = ml.lines[1:]
+	}
+
+	for _, f := range ml.followers {
+		// Followers are buffered; if a reader isn't keeping up we drop the
+		// line for them rather than blocking log ingestion.
+		select {
+		case f <- line:
+		default:
+		}
+	}
+}
+
+// Tail returns the last n lines logged for the given manifest.
+func (s *LogStore) Tail(name model.ManifestName, n int) []LogLine {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ml, ok := s.logs[name]
+	if !ok {
+		return nil
+	}
+	if n <= 0 || n >= len(ml.lines) {
+		return append([]LogLine{}, ml.lines...)
+	}
+	return append([]LogLine{}, ml.lines[len(ml.lines)-n:]...)
+}
+
+// Since returns all lines logged for the given manifest at or after t.
+func (s *LogStore) Since(name model.ManifestName, t time.Time) []LogLine {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ml, ok := s.logs[name]
+	if !ok {
+		return nil
+	}
+
+	result := make([]LogLine, 0, len(ml.lines))
+	for _, l := range ml.lines {
+		if !l.Time.Before(t) {
+			result = append(result, l)
+		}
+	}
+	return result
+}
+
+// Follow returns a channel of new lines logged for the given manifest from
+// this point forward. The channel is closed when ctx is done.
+func (s *LogStore) Follow(ctx context.Context, name model.ManifestName) <-chan LogLine {
+	s.mu.Lock()
+	ml, ok := s.logs[name]
+	if !ok {
+		ml = &manifestLog{}
+		s.logs[name] = ml
+	}
+	ch := make(chan LogLine, 100)
+	ml.followers = append(ml.followers, ch)
+	s.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for i, f := range ml.followers {
+			if f == ch {
+				ml.followers = append(ml.followers[:i], ml.followers[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+
+	return ch
+}
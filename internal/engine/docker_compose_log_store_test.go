@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/windmilleng/tilt/internal/model"
+)
+
+func TestLogStoreTail(t *testing.T) {
+	s := NewLogStore(0)
+	name := model.ManifestName("web")
+
+	base := time.Date(2019, 1, 2, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		s.Append(LogLine{ManifestName: name, Time: base.Add(time.Duration(i) * time.Second), Text: []byte("line")})
+	}
+
+	tail := s.Tail(name, 2)
+	if len(tail) != 2 {
+		t.Fatalf("Tail(2) returned %d lines, want 2", len(tail))
+	}
+	if !tail[len(tail)-1].Time.Equal(base.Add(4 * time.Second)) {
+		t.Errorf("Tail(2) last line time = %v, want %v", tail[len(tail)-1].Time, base.Add(4*time.Second))
+	}
+
+	all := s.Tail(name, 0)
+	if len(all) != 5 {
+		t.Errorf("Tail(0) returned %d lines, want all 5", len(all))
+	}
+}
+
+func TestLogStoreSince(t *testing.T) {
+	s := NewLogStore(0)
+	name := model.ManifestName("web")
+
+	base := time.Date(2019, 1, 2, 0, 0, 0, 0, time.UTC)
+	s.Append(LogLine{ManifestName: name, Time: base, Text: []byte("old")})
+	s.Append(LogLine{ManifestName: name, Time: base.Add(time.Minute), Text: []byte("new")})
+
+	got := s.Since(name, base.Add(30*time.Second))
+	if len(got) != 1 || string(got[0].Text) != "new" {
+		t.Errorf("Since() = %+v, want only the \"new\" line", got)
+	}
+}
+
+func TestLogStoreEvictsByByteCap(t *testing.T) {
+	s := NewLogStore(10)
+	name := model.ManifestName("web")
+
+	s.Append(LogLine{ManifestName: name, Text: []byte("0123456789")})
+	s.Append(LogLine{ManifestName: name, Text: []byte("abcde")})
+
+	tail := s.Tail(name, 0)
+	if len(tail) != 1 {
+		t.Fatalf("expected the first line to be evicted once the byte cap is exceeded, got %d lines", len(tail))
+	}
+	if string(tail[0].Text) != "abcde" {
+		t.Errorf("expected the most recent line to survive, got %q", tail[0].Text)
+	}
+}
+
+func TestLogStoreFollow(t *testing.T) {
+	s := NewLogStore(0)
+	name := model.ManifestName("web")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := s.Follow(ctx, name)
+	s.Append(LogLine{ManifestName: name, Text: []byte("hello")})
+
+	select {
+	case l := <-ch:
+		if string(l.Text) != "hello" {
+			t.Errorf("Follow() got %q, want %q", l.Text, "hello")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for followed log line")
+	}
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Errorf("expected Follow() channel to close after ctx is done")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for Follow() channel to close")
+	}
+}
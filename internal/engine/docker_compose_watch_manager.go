@@ -0,0 +1,287 @@
+package engine
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/windmilleng/tilt/internal/dockercompose"
+	"github.com/windmilleng/tilt/internal/logger"
+	"github.com/windmilleng/tilt/internal/model"
+	"github.com/windmilleng/tilt/internal/store"
+	"github.com/windmilleng/tilt/internal/watch"
+)
+
+// How long we wait for more filesystem events before syncing a batch of
+// changes into a service's container. Mirrors the debounce window used
+// elsewhere in the engine for file watching.
+const dcWatchDebounceDuration = 200 * time.Millisecond
+
+// Watches the files declared in a docker-compose service's `watch:` block,
+// and syncs (or restarts, or rebuilds) the service's container when they
+// change.
+//
+// This is the sibling of DockerComposeLogManager: where that subsystem pulls
+// logs out of docker-compose, this one pushes file changes in.
+type DockerComposeWatchManager struct {
+	watches map[model.ManifestName]dockerComposeFileWatch
+	dcc     dockercompose.DockerComposeClient
+
+	// newWatcher builds the Notify watchFiles watches for changes on.
+	// Overridable so tests can inject a fake Notify instead of watching the
+	// real filesystem.
+	newWatcher func(paths []string, ignore []string) (watch.Notify, error)
+}
+
+func NewDockerComposeWatchManager(dcc dockercompose.DockerComposeClient) *DockerComposeWatchManager {
+	return &DockerComposeWatchManager{
+		watches:    make(map[model.ManifestName]dockerComposeFileWatch),
+		dcc:        dcc,
+		newWatcher: watch.NewWatcher,
+	}
+}
+
+// Diff the current watches against the set of current docker-compose
+// services with a `watch:` block configured, returning the changes we need
+// to make.
+func (m *DockerComposeWatchManager) diff(ctx context.Context, st store.RStore) (setup []dockerComposeFileWatch, teardown []dockerComposeFileWatch) {
+	state := st.RLockState()
+	defer st.RUnlockState()
+
+	// If we're not watching the mounts, then don't bother watching for
+	// docker-compose file sync either.
+	if !state.WatchMounts {
+		return nil, nil
+	}
+
+	for _, ms := range state.ManifestStates {
+		if !ms.Manifest.IsDC() {
+			continue
+		}
+		dcInfo := ms.Manifest.DCInfo()
+		if !dcInfo.Watch.Enabled() {
+			continue
+		}
+
+		if existing, isActive := m.watches[ms.Manifest.Name]; isActive {
+			if existing.ctx.Err() == nil {
+				// Watcher is still active, no action needed.
+				continue
+			}
+			// The watcher died (e.g. watchFiles failed to start), so fall
+			// through and set up a new one.
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		w := dockerComposeFileWatch{
+			ctx:          ctx,
+			cancel:       cancel,
+			name:         ms.Manifest.Name,
+			dcConfigPath: dcInfo.ConfigPath,
+			spec:         dcInfo.Watch,
+		}
+		m.watches[ms.Manifest.Name] = w
+		setup = append(setup, w)
+	}
+
+	for key, value := range m.watches {
+		_, inState := state.ManifestStates[key]
+		if !inState {
+			delete(m.watches, key)
+			teardown = append(teardown, value)
+		}
+	}
+
+	return setup, teardown
+}
+
+func (m *DockerComposeWatchManager) OnChange(ctx context.Context, st store.RStore) {
+	setup, teardown := m.diff(ctx, st)
+	for _, w := range teardown {
+		w.cancel()
+	}
+
+	for _, w := range setup {
+		go m.watchFiles(w, st)
+	}
+}
+
+// watchFiles runs the watch loop for a single service. If it can't even get
+// started, it cancels w's context before returning so diff() sees the watch
+// as dead (via ctx.Err()) and retries it on the next OnChange, instead of
+// leaving the service's file sync silently stuck for the rest of the
+// session.
+func (m *DockerComposeWatchManager) watchFiles(w dockerComposeFileWatch, st store.RStore) {
+	notify, err := m.newWatcher(w.spec.Paths, w.spec.Ignore)
+	if err != nil {
+		logger.Get(w.ctx).Infof("Error watching files for %s: %v", w.name, err)
+		w.cancel()
+		return
+	}
+	defer func() {
+		_ = notify.Close()
+	}()
+
+	if err := notify.Start(); err != nil {
+		logger.Get(w.ctx).Infof("Error watching files for %s: %v", w.name, err)
+		w.cancel()
+		return
+	}
+
+	var pending []string
+	var debounce <-chan time.Time
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+
+		case err := <-notify.Errors():
+			logger.Get(w.ctx).Infof("Error watching files for %s: %v", w.name, err)
+
+		case e := <-notify.Events():
+			pending = append(pending, e.Path())
+			debounce = time.After(dcWatchDebounceDuration)
+
+		case <-debounce:
+			changed := dedupePaths(pending)
+			pending = nil
+			debounce = nil
+			m.sync(w, st, changed)
+		}
+	}
+}
+
+// Batch the changed paths into a single tar stream and apply the
+// configured action (sync, sync+restart, or rebuild) for the service.
+func (m *DockerComposeWatchManager) sync(w dockerComposeFileWatch, st store.RStore, changed []string) {
+	st.Dispatch(DockerComposeSyncStartedAction{ManifestName: w.name, Paths: changed})
+
+	tarBuf, err := tarPaths(w.spec.Root, changed)
+	if err != nil {
+		logger.Get(w.ctx).Infof("Error building sync tarball for %s: %v", w.name, err)
+		return
+	}
+
+	err = m.dcc.StreamTarToContainer(w.ctx, w.dcConfigPath, w.name.String(), tarBuf)
+	if err != nil {
+		logger.Get(w.ctx).Infof("Error syncing files to %s: %v", w.name, err)
+		st.Dispatch(DockerComposeSyncErrorAction{ManifestName: w.name, Error: err.Error()})
+		return
+	}
+
+	switch w.spec.Action {
+	case model.DCWatchActionRebuild:
+		err = m.dcc.Up(w.ctx, w.dcConfigPath, w.name.String(), true)
+	case model.DCWatchActionSyncRestart:
+		err = m.dcc.Restart(w.ctx, w.dcConfigPath, w.name.String())
+	}
+	if err != nil {
+		logger.Get(w.ctx).Infof("Error applying %s watch action for %s: %v", w.spec.Action, w.name, err)
+		st.Dispatch(DockerComposeSyncErrorAction{ManifestName: w.name, Error: err.Error()})
+		return
+	}
+
+	st.Dispatch(DockerComposeSyncCompleteAction{ManifestName: w.name, Paths: changed})
+}
+
+// Tar up the given paths (relative to root) into a single in-memory
+// archive, so the whole batch can go over the wire in one `docker cp`
+// instead of one invocation per file.
+func tarPaths(root string, paths []string) (*bytes.Buffer, error) {
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	for _, p := range paths {
+		if err := addFileToTar(tw, root, p); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func addFileToTar(tw *tar.Writer, root string, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// The file was deleted between the watch event firing and the
+			// sync running; nothing to send for it.
+			return nil
+		}
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	relPath, err := filepath.Rel(root, path)
+	if err != nil {
+		return err
+	}
+
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = filepath.ToSlash(relPath)
+
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+func dedupePaths(paths []string) []string {
+	seen := make(map[string]bool, len(paths))
+	result := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if !seen[p] {
+			seen[p] = true
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+type dockerComposeFileWatch struct {
+	ctx          context.Context
+	cancel       func()
+	name         model.ManifestName
+	dcConfigPath string
+	spec         model.DockerComposeWatchSpec
+}
+
+var _ store.Subscriber = &DockerComposeWatchManager{}
+
+type DockerComposeSyncStartedAction struct {
+	ManifestName model.ManifestName
+	Paths        []string
+}
+
+func (DockerComposeSyncStartedAction) Action() {}
+
+type DockerComposeSyncCompleteAction struct {
+	ManifestName model.ManifestName
+	Paths        []string
+}
+
+func (DockerComposeSyncCompleteAction) Action() {}
+
+type DockerComposeSyncErrorAction struct {
+	ManifestName model.ManifestName
+	Error        string
+}
+
+func (DockerComposeSyncErrorAction) Action() {}
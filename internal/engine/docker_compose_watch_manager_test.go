@@ -0,0 +1,423 @@
+package engine
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/windmilleng/tilt/internal/model"
+	"github.com/windmilleng/tilt/internal/store"
+	"github.com/windmilleng/tilt/internal/watch"
+)
+
+func dcManifest(name string, watchSpec model.DockerComposeWatchSpec) model.Manifest {
+	return model.NewManifest(model.ManifestName(name)).WithDCInfo(model.DCInfo{
+		ConfigPath: "docker-compose.yml",
+		Watch:      watchSpec,
+	})
+}
+
+func TestDiffSkipsWhenNotWatchingMounts(t *testing.T) {
+	m := NewDockerComposeWatchManager(&fakeDCC{})
+	st := newFakeStore()
+	st.state.WatchMounts = false
+	st.state.ManifestStates["web"] = &store.ManifestState{
+		Manifest: dcManifest("web", model.DockerComposeWatchSpec{Paths: []string{"."}}),
+	}
+
+	setup, teardown := m.diff(context.Background(), st)
+	if len(setup) != 0 || len(teardown) != 0 {
+		t.Fatalf("diff() with WatchMounts=false = (%v, %v), want no changes", setup, teardown)
+	}
+}
+
+func TestDiffSetupSkipsNonDCAndNoWatchSpec(t *testing.T) {
+	m := NewDockerComposeWatchManager(&fakeDCC{})
+	st := newFakeStore()
+	st.state.WatchMounts = true
+	st.state.ManifestStates["k8s"] = &store.ManifestState{Manifest: model.NewManifest("k8s")}
+	st.state.ManifestStates["dc-no-watch"] = &store.ManifestState{Manifest: dcManifest("dc-no-watch", model.DockerComposeWatchSpec{})}
+	st.state.ManifestStates["dc-watch"] = &store.ManifestState{Manifest: dcManifest("dc-watch", model.DockerComposeWatchSpec{Paths: []string{"."}})}
+
+	setup, teardown := m.diff(context.Background(), st)
+	if len(teardown) != 0 {
+		t.Fatalf("expected no teardowns, got %v", teardown)
+	}
+	if len(setup) != 1 || setup[0].name != "dc-watch" {
+		t.Fatalf("diff() setup = %v, want only dc-watch", setup)
+	}
+}
+
+func TestDiffTeardownsRemovedManifest(t *testing.T) {
+	m := NewDockerComposeWatchManager(&fakeDCC{})
+	st := newFakeStore()
+	st.state.WatchMounts = true
+	st.state.ManifestStates["dc-watch"] = &store.ManifestState{Manifest: dcManifest("dc-watch", model.DockerComposeWatchSpec{Paths: []string{"."}})}
+
+	setup, _ := m.diff(context.Background(), st)
+	if len(setup) != 1 {
+		t.Fatalf("expected one watch set up, got %v", setup)
+	}
+
+	delete(st.state.ManifestStates, "dc-watch")
+	setup, teardown := m.diff(context.Background(), st)
+	if len(setup) != 0 {
+		t.Fatalf("expected no new watches, got %v", setup)
+	}
+	if len(teardown) != 1 || teardown[0].name != "dc-watch" {
+		t.Fatalf("expected dc-watch to be torn down, got %v", teardown)
+	}
+}
+
+func TestDiffRetriesADeadWatch(t *testing.T) {
+	m := NewDockerComposeWatchManager(&fakeDCC{})
+	st := newFakeStore()
+	st.state.WatchMounts = true
+	st.state.ManifestStates["dc-watch"] = &store.ManifestState{Manifest: dcManifest("dc-watch", model.DockerComposeWatchSpec{Paths: []string{"."}})}
+
+	setup, _ := m.diff(context.Background(), st)
+	if len(setup) != 1 {
+		t.Fatalf("expected one watch set up, got %v", setup)
+	}
+	firstCtx := setup[0].ctx
+
+	// No-op diff while the watch is still alive.
+	setup, _ = m.diff(context.Background(), st)
+	if len(setup) != 0 {
+		t.Fatalf("expected no new watch while the existing one is alive, got %v", setup)
+	}
+
+	// Simulate watchFiles failing to start (e.g. watch.NewWatcher error).
+	m.watches["dc-watch"].cancel()
+
+	setup, _ = m.diff(context.Background(), st)
+	if len(setup) != 1 {
+		t.Fatalf("expected diff() to retry the dead watch, got %v", setup)
+	}
+	if setup[0].ctx == firstCtx {
+		t.Errorf("expected a fresh context for the retried watch")
+	}
+}
+
+func TestOnChangeCancelsTornDownWatches(t *testing.T) {
+	m := NewDockerComposeWatchManager(&fakeDCC{})
+	m.newWatcher = func(paths, ignore []string) (watch.Notify, error) {
+		return newFakeNotify(), nil
+	}
+
+	st := newFakeStore()
+	st.state.WatchMounts = true
+	st.state.ManifestStates["dc-watch"] = &store.ManifestState{Manifest: dcManifest("dc-watch", model.DockerComposeWatchSpec{Paths: []string{"."}})}
+
+	m.OnChange(context.Background(), st)
+
+	w := m.watches["dc-watch"]
+	if w.ctx.Err() != nil {
+		t.Fatalf("expected the newly set-up watch to still be alive")
+	}
+
+	delete(st.state.ManifestStates, "dc-watch")
+	m.OnChange(context.Background(), st)
+
+	if w.ctx.Err() == nil {
+		t.Errorf("expected OnChange to cancel a watch whose manifest was removed")
+	}
+}
+
+func TestWatchFilesCancelsOnFailedStart(t *testing.T) {
+	m := NewDockerComposeWatchManager(&fakeDCC{})
+	m.newWatcher = func(paths, ignore []string) (watch.Notify, error) {
+		return nil, errors.New("boom")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := dockerComposeFileWatch{ctx: ctx, cancel: cancel, name: "dc-watch"}
+
+	m.watchFiles(w, newFakeStore())
+
+	if w.ctx.Err() == nil {
+		t.Errorf("expected watchFiles to cancel the watch's context when the watcher fails to start")
+	}
+}
+
+func TestWatchFilesCancelsWhenNotifyFailsToStart(t *testing.T) {
+	m := NewDockerComposeWatchManager(&fakeDCC{})
+	notify := newFakeNotify()
+	notify.startErr = errors.New("boom")
+	m.newWatcher = func(paths, ignore []string) (watch.Notify, error) {
+		return notify, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := dockerComposeFileWatch{ctx: ctx, cancel: cancel, name: "dc-watch"}
+
+	m.watchFiles(w, newFakeStore())
+
+	if w.ctx.Err() == nil {
+		t.Errorf("expected watchFiles to cancel the watch's context when Start() fails")
+	}
+	if !notify.closed {
+		t.Errorf("expected watchFiles to close the Notify it created, even on a failed start")
+	}
+}
+
+func TestWatchFilesDebouncesAndBatchesSync(t *testing.T) {
+	root := t.TempDir()
+	write(t, filepath.Join(root, "a.txt"), "a")
+	write(t, filepath.Join(root, "b.txt"), "b")
+
+	dcc := &fakeDCC{}
+	m := NewDockerComposeWatchManager(dcc)
+	notify := newFakeNotify()
+	m.newWatcher = func(paths, ignore []string) (watch.Notify, error) {
+		return notify, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := dockerComposeFileWatch{
+		ctx:          ctx,
+		cancel:       cancel,
+		name:         "dc-watch",
+		dcConfigPath: "docker-compose.yml",
+		spec: model.DockerComposeWatchSpec{
+			Root:   root,
+			Paths:  []string{filepath.Join(root, "a.txt"), filepath.Join(root, "b.txt")},
+			Action: model.DCWatchActionSyncRestart,
+		},
+	}
+
+	st := newFakeStore()
+	done := make(chan struct{})
+	go func() {
+		m.watchFiles(w, st)
+		close(done)
+	}()
+
+	// Fire two events for the same path within the debounce window -- they
+	// should collapse into a single sync call.
+	notify.events <- fakePathEvent{path: filepath.Join(root, "a.txt")}
+	notify.events <- fakePathEvent{path: filepath.Join(root, "a.txt")}
+	notify.events <- fakePathEvent{path: filepath.Join(root, "b.txt")}
+
+	deadline := time.After(2 * time.Second)
+	for {
+		dcc.mu.Lock()
+		n := dcc.streamTarToContainerNr
+		dcc.mu.Unlock()
+		if n >= 1 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("timed out waiting for sync() to batch the debounced events")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	cancel()
+	<-done
+
+	dcc.mu.Lock()
+	defer dcc.mu.Unlock()
+	if dcc.streamTarToContainerNr != 1 {
+		t.Errorf("StreamTarToContainer called %d times, want exactly 1 (debounced batch)", dcc.streamTarToContainerNr)
+	}
+	if dcc.restartCalls != 1 {
+		t.Errorf("Restart called %d times, want 1 for DCWatchActionSyncRestart", dcc.restartCalls)
+	}
+	if dcc.upCalls != 0 {
+		t.Errorf("Up called %d times, want 0 for DCWatchActionSyncRestart", dcc.upCalls)
+	}
+}
+
+func TestSyncDispatchesRebuildAction(t *testing.T) {
+	root := t.TempDir()
+	write(t, filepath.Join(root, "a.txt"), "a")
+
+	dcc := &fakeDCC{}
+	m := NewDockerComposeWatchManager(dcc)
+	st := newFakeStore()
+
+	w := dockerComposeFileWatch{
+		ctx:  context.Background(),
+		name: "dc-watch",
+		spec: model.DockerComposeWatchSpec{Root: root, Action: model.DCWatchActionRebuild},
+	}
+	m.sync(w, st, []string{filepath.Join(root, "a.txt")})
+
+	if dcc.upCalls != 1 {
+		t.Errorf("Up called %d times, want 1 for DCWatchActionRebuild", dcc.upCalls)
+	}
+	if dcc.restartCalls != 0 {
+		t.Errorf("Restart called %d times, want 0 for DCWatchActionRebuild", dcc.restartCalls)
+	}
+
+	actions := st.dispatchedActions()
+	if len(actions) != 2 {
+		t.Fatalf("dispatched %d actions, want 2 (started, complete)", len(actions))
+	}
+	if _, ok := actions[0].(DockerComposeSyncStartedAction); !ok {
+		t.Errorf("actions[0] = %T, want DockerComposeSyncStartedAction", actions[0])
+	}
+	if _, ok := actions[1].(DockerComposeSyncCompleteAction); !ok {
+		t.Errorf("actions[1] = %T, want DockerComposeSyncCompleteAction", actions[1])
+	}
+}
+
+func TestSyncDispatchesErrorActionOnStreamTarFailure(t *testing.T) {
+	root := t.TempDir()
+	dcc := &fakeDCC{streamTarToContainerErr: errors.New("container gone")}
+	m := NewDockerComposeWatchManager(dcc)
+	st := newFakeStore()
+
+	w := dockerComposeFileWatch{
+		ctx:  context.Background(),
+		name: "dc-watch",
+		spec: model.DockerComposeWatchSpec{Root: root, Action: model.DCWatchActionSyncRestart},
+	}
+	m.sync(w, st, nil)
+
+	if dcc.upCalls != 0 || dcc.restartCalls != 0 {
+		t.Errorf("expected no watch action to run after a StreamTarToContainer failure, got up=%d restart=%d", dcc.upCalls, dcc.restartCalls)
+	}
+
+	actions := st.dispatchedActions()
+	if len(actions) != 2 {
+		t.Fatalf("dispatched %d actions, want 2 (started, error)", len(actions))
+	}
+	errAction, ok := actions[1].(DockerComposeSyncErrorAction)
+	if !ok {
+		t.Fatalf("actions[1] = %T, want DockerComposeSyncErrorAction", actions[1])
+	}
+	if errAction.Error != "container gone" {
+		t.Errorf("error action = %q, want %q", errAction.Error, "container gone")
+	}
+}
+
+func TestSyncDispatchesErrorActionOnWatchActionFailure(t *testing.T) {
+	root := t.TempDir()
+	dcc := &fakeDCC{restartErr: errors.New("restart failed")}
+	m := NewDockerComposeWatchManager(dcc)
+	st := newFakeStore()
+
+	w := dockerComposeFileWatch{
+		ctx:  context.Background(),
+		name: "dc-watch",
+		spec: model.DockerComposeWatchSpec{Root: root, Action: model.DCWatchActionSyncRestart},
+	}
+	m.sync(w, st, nil)
+
+	actions := st.dispatchedActions()
+	if len(actions) != 2 {
+		t.Fatalf("dispatched %d actions, want 2 (started, error)", len(actions))
+	}
+	if _, ok := actions[1].(DockerComposeSyncErrorAction); !ok {
+		t.Fatalf("actions[1] = %T, want DockerComposeSyncErrorAction", actions[1])
+	}
+}
+
+func TestDedupePaths(t *testing.T) {
+	in := []string{"a.txt", "b.txt", "a.txt", "c.txt", "b.txt"}
+	got := dedupePaths(in)
+	want := []string{"a.txt", "b.txt", "c.txt"}
+
+	if len(got) != len(want) {
+		t.Fatalf("dedupePaths(%v) = %v, want %v", in, got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("dedupePaths(%v) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestTarPaths(t *testing.T) {
+	root := t.TempDir()
+	write(t, filepath.Join(root, "foo.txt"), "hello")
+	write(t, filepath.Join(root, "nested", "bar.txt"), "world")
+
+	buf, err := tarPaths(root, []string{
+		filepath.Join(root, "foo.txt"),
+		filepath.Join(root, "nested", "bar.txt"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]string{}
+	tr := tar.NewReader(buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[hdr.Name] = string(contents)
+	}
+
+	want := map[string]string{
+		"foo.txt":        "hello",
+		"nested/bar.txt": "world",
+	}
+	for name, contents := range want {
+		if got[name] != contents {
+			t.Errorf("tar entry %q = %q, want %q", name, got[name], contents)
+		}
+	}
+}
+
+// TestTarPathsSkipsDeletedFile covers the race between a watch event firing
+// and the debounced sync running, where the file may no longer exist.
+func TestTarPathsSkipsDeletedFile(t *testing.T) {
+	root := t.TempDir()
+	write(t, filepath.Join(root, "foo.txt"), "hello")
+
+	buf, err := tarPaths(root, []string{
+		filepath.Join(root, "foo.txt"),
+		filepath.Join(root, "gone.txt"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := map[string]bool{}
+	tr := tar.NewReader(buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names[hdr.Name] = true
+	}
+
+	if !names["foo.txt"] {
+		t.Errorf("expected tar to contain foo.txt, got %v", names)
+	}
+	if names["gone.txt"] {
+		t.Errorf("expected tar to skip deleted gone.txt, got %v", names)
+	}
+}
+
+func write(t *testing.T, path string, contents string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(contents), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/windmilleng/tilt/internal/dockercompose"
+)
+
+var _ dockercompose.DockerComposeClient = &fakeDCC{}
+
+// fakeDCC is a minimal dockercompose.DockerComposeClient for testing
+// DockerComposeWatchManager/DockerComposeLogManager without shelling out.
+type fakeDCC struct {
+	mu sync.Mutex
+
+	streamTarToContainerErr error
+	upErr                   error
+	restartErr              error
+
+	upCalls                int
+	restartCalls           int
+	streamTarToContainer   []byte
+	streamTarToContainerNr int
+}
+
+func (f *fakeDCC) Up(ctx context.Context, configPath string, serviceName string, shouldBuild bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.upCalls++
+	return f.upErr
+}
+
+func (f *fakeDCC) Restart(ctx context.Context, configPath string, serviceName string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.restartCalls++
+	return f.restartErr
+}
+
+func (f *fakeDCC) StreamLogs(ctx context.Context, configPath string, serviceName string, since time.Time) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(nil)), nil
+}
+
+func (f *fakeDCC) StreamTarToContainer(ctx context.Context, configPath string, serviceName string, tar io.Reader) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.streamTarToContainerNr++
+	if f.streamTarToContainerErr != nil {
+		return f.streamTarToContainerErr
+	}
+	contents, err := io.ReadAll(tar)
+	if err != nil {
+		return err
+	}
+	f.streamTarToContainer = contents
+	return nil
+}
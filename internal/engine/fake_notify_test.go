@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"github.com/windmilleng/tilt/internal/watch"
+)
+
+type fakePathEvent struct{ path string }
+
+func (e fakePathEvent) Path() string { return e.path }
+
+// fakeNotify is a scriptable watch.Notify for testing
+// DockerComposeWatchManager's debounce/batch loop without a real
+// filesystem watcher.
+type fakeNotify struct {
+	startErr error
+	closed   bool
+
+	events chan watch.Event
+	errs   chan error
+}
+
+func newFakeNotify() *fakeNotify {
+	return &fakeNotify{
+		events: make(chan watch.Event, 10),
+		errs:   make(chan error, 10),
+	}
+}
+
+func (n *fakeNotify) Start() error { return n.startErr }
+
+func (n *fakeNotify) Close() error {
+	n.closed = true
+	return nil
+}
+
+func (n *fakeNotify) Events() <-chan watch.Event { return n.events }
+func (n *fakeNotify) Errors() <-chan error       { return n.errs }
+
+var _ watch.Notify = &fakeNotify{}
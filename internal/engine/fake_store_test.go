@@ -0,0 +1,45 @@
+package engine
+
+import (
+	"sync"
+
+	"github.com/windmilleng/tilt/internal/model"
+	"github.com/windmilleng/tilt/internal/store"
+)
+
+// fakeStore is a minimal in-memory store.RStore for testing Subscribers
+// without a real engine Store.
+type fakeStore struct {
+	mu      sync.Mutex
+	state   store.EngineState
+	actions []store.Action
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		state: store.EngineState{
+			ManifestStates: make(map[model.ManifestName]*store.ManifestState),
+		},
+	}
+}
+
+func (s *fakeStore) RLockState() store.EngineState {
+	s.mu.Lock()
+	return s.state
+}
+
+func (s *fakeStore) RUnlockState() {
+	s.mu.Unlock()
+}
+
+func (s *fakeStore) Dispatch(a store.Action) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.actions = append(s.actions, a)
+}
+
+func (s *fakeStore) dispatchedActions() []store.Action {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]store.Action{}, s.actions...)
+}
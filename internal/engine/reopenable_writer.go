@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// A func that (re-)opens the underlying io.WriteCloser a ReopenableWriter
+// writes to, e.g. re-opening a log file by path.
+type WriterOpener func() (io.WriteCloser, error)
+
+// Wraps a writer behind a mutex and listens for SIGHUP (and, optionally,
+// SIGCONT) to close and reopen it. This lets Tilt play nicely with
+// `logrotate` when its log destination is a file: without it, Tilt would
+// keep writing to the deleted inode after rotation instead of the new file.
+type ReopenableWriter struct {
+	mu     sync.Mutex
+	open   WriterOpener
+	w      io.WriteCloser
+	sigCh  chan os.Signal
+	doneCh chan struct{}
+}
+
+// NewReopenableWriter opens w via open, and starts listening for SIGHUP (and
+// SIGCONT, if withSIGCONT is true) to reopen it.
+func NewReopenableWriter(open WriterOpener, withSIGCONT bool) (*ReopenableWriter, error) {
+	w, err := open()
+	if err != nil {
+		return nil, err
+	}
+
+	sigs := []os.Signal{syscall.SIGHUP}
+	if withSIGCONT {
+		sigs = append(sigs, syscall.SIGCONT)
+	}
+
+	rw := &ReopenableWriter{
+		open:   open,
+		w:      w,
+		sigCh:  make(chan os.Signal, 1),
+		doneCh: make(chan struct{}),
+	}
+	signal.Notify(rw.sigCh, sigs...)
+	go rw.listenForSignals()
+
+	return rw, nil
+}
+
+func (rw *ReopenableWriter) listenForSignals() {
+	for {
+		select {
+		case <-rw.sigCh:
+			_ = rw.Reopen()
+		case <-rw.doneCh:
+			return
+		}
+	}
+}
+
+func (rw *ReopenableWriter) Write(p []byte) (n int, err error) {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	return rw.w.Write(p)
+}
+
+// Reopen closes and reopens the underlying writer. Exported so it can also
+// be triggered programmatically, e.g. from a store action in tests.
+func (rw *ReopenableWriter) Reopen() error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	newW, err := rw.open()
+	if err != nil {
+		return err
+	}
+
+	_ = rw.w.Close()
+	rw.w = newW
+	return nil
+}
+
+func (rw *ReopenableWriter) Close() error {
+	signal.Stop(rw.sigCh)
+	close(rw.doneCh)
+
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	return rw.w.Close()
+}
+
+// nopWriteCloser adapts an io.Writer that doesn't need closing (e.g. an
+// in-process logger sink) to the io.WriteCloser a WriterOpener returns.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,45 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReopenableWriterReopensByPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tilt.log")
+
+	m := NewDockerComposeLogManager(nil, nil, LogFormatPlain, path)
+	w, err := m.getGlobalWriter(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = w.Close() }()
+
+	if _, err := w.Write([]byte("before rotate\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate logrotate: move the file Tilt has open out from under it, as
+	// if a rotation had just happened.
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Write([]byte("after rotate\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected Reopen() to recreate %s: %v", path, err)
+	}
+	if string(contents) != "after rotate\n" {
+		t.Errorf("reopened log file = %q, want %q", contents, "after rotate\n")
+	}
+}
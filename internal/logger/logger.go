@@ -0,0 +1,61 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Level selects how severe a log line is, mirroring the levels a Logger's
+// underlying writer can be split by (e.g. routing InfoLvl to stdout and a
+// log file simultaneously).
+type Level int
+
+const (
+	InfoLvl Level = iota
+	DebugLvl
+)
+
+// Logger is Tilt's basic structured-ish logging interface: callers log a
+// formatted line at a level, and can grab the raw io.Writer backing a level
+// when they need to hand it to something else (e.g. as the sink a
+// ReopenableWriter wraps).
+type Logger interface {
+	Infof(format string, a ...interface{})
+	Writer(level Level) io.Writer
+}
+
+type logger struct {
+	writer io.Writer
+}
+
+func NewLogger(w io.Writer) Logger {
+	return logger{writer: w}
+}
+
+func (l logger) Infof(format string, a ...interface{}) {
+	fmt.Fprintf(l.writer, format+"\n", a...)
+}
+
+func (l logger) Writer(level Level) io.Writer {
+	return l.writer
+}
+
+type ctxKey struct{}
+
+// WithLogger returns a context carrying l, retrievable later via Get.
+func WithLogger(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// Get returns the Logger attached to ctx via WithLogger, or a logger that
+// writes to stderr if none was attached (e.g. in tests that don't care
+// about log output).
+func Get(ctx context.Context) Logger {
+	l, ok := ctx.Value(ctxKey{}).(Logger)
+	if !ok {
+		return NewLogger(os.Stderr)
+	}
+	return l
+}
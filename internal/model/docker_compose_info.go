@@ -0,0 +1,12 @@
+package model
+
+// DCInfo is the subset of a docker-compose service's parsed configuration
+// that the engine needs to manage its lifecycle (logs, file sync, restarts).
+type DCInfo struct {
+	// ConfigPath is the path to the docker-compose file declaring this
+	// service.
+	ConfigPath string
+
+	// Watch is the parsed `watch:` block for this service, if any.
+	Watch DockerComposeWatchSpec
+}
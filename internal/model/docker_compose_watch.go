@@ -0,0 +1,40 @@
+package model
+
+// DCWatchAction is the action DockerComposeWatchManager takes against a
+// service's container after syncing changed files into it.
+type DCWatchAction string
+
+const (
+	// DCWatchActionSyncRestart restarts the service's container in place
+	// after syncing, without rebuilding its image.
+	DCWatchActionSyncRestart DCWatchAction = "sync-restart"
+
+	// DCWatchActionRebuild rebuilds the service's image and brings it back
+	// up after syncing.
+	DCWatchActionRebuild DCWatchAction = "rebuild"
+)
+
+// DockerComposeWatchSpec is the parsed form of a docker-compose service's
+// `watch:` block: which local paths to sync into the container, and what to
+// do once they're synced.
+type DockerComposeWatchSpec struct {
+	// Root is the local directory that synced files' paths inside the
+	// container are computed relative to. Paths must lie under it.
+	Root string
+
+	// Paths are the local paths to watch for changes, used as given (not
+	// joined onto Root).
+	Paths []string
+
+	// Ignore are entries of Paths to exclude, matched exactly against the
+	// Paths strings (not globs, and not resolved against Root).
+	Ignore []string
+
+	Action DCWatchAction
+}
+
+// Enabled reports whether this service has a `watch:` block configured at
+// all, vs. the zero value we get back for services that don't.
+func (s DockerComposeWatchSpec) Enabled() bool {
+	return len(s.Paths) > 0
+}
@@ -0,0 +1,41 @@
+package model
+
+// ManifestName identifies a single resource (service, image, etc.) Tilt is
+// managing.
+type ManifestName string
+
+func (m ManifestName) String() string {
+	return string(m)
+}
+
+// Manifest is Tilt's in-memory representation of one resource's
+// configuration.
+type Manifest struct {
+	Name ManifestName
+
+	dcInfo   DCInfo
+	isDCInfo bool
+}
+
+// NewManifest returns a non-docker-compose manifest with the given name.
+func NewManifest(name ManifestName) Manifest {
+	return Manifest{Name: name}
+}
+
+// WithDCInfo returns a copy of m configured as a docker-compose service.
+func (m Manifest) WithDCInfo(info DCInfo) Manifest {
+	m.dcInfo = info
+	m.isDCInfo = true
+	return m
+}
+
+// IsDC reports whether this manifest describes a docker-compose service.
+func (m Manifest) IsDC() bool {
+	return m.isDCInfo
+}
+
+// DCInfo returns this manifest's docker-compose configuration. Only
+// meaningful when IsDC() is true.
+func (m Manifest) DCInfo() DCInfo {
+	return m.dcInfo
+}
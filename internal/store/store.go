@@ -0,0 +1,41 @@
+package store
+
+import (
+	"context"
+
+	"github.com/windmilleng/tilt/internal/model"
+)
+
+// Action is dispatched to a Store to record an update to engine state.
+type Action interface {
+	Action()
+}
+
+// ManifestState tracks the runtime state of a single manifest.
+type ManifestState struct {
+	Manifest model.Manifest
+}
+
+// EngineState is Tilt's global in-memory state: the manifests it knows
+// about and their runtime status.
+type EngineState struct {
+	// WatchMounts is false until the initial build of every manifest has
+	// completed, so subscribers that watch for live changes (file sync,
+	// logs) don't start racing the first build.
+	WatchMounts bool
+
+	ManifestStates map[model.ManifestName]*ManifestState
+}
+
+// RStore is the read/dispatch surface a Subscriber uses to read engine
+// state and queue updates to it.
+type RStore interface {
+	RLockState() EngineState
+	RUnlockState()
+	Dispatch(action Action)
+}
+
+// Subscriber is notified by the Store whenever engine state changes.
+type Subscriber interface {
+	OnChange(ctx context.Context, st RStore)
+}
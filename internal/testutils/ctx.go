@@ -0,0 +1,15 @@
+package testutils
+
+import (
+	"context"
+	"io/ioutil"
+
+	"github.com/windmilleng/tilt/internal/logger"
+)
+
+// CtxForTest returns a context.Context for use by test fixtures, with a
+// Logger attached that discards its output -- so code under test that calls
+// logger.Get(ctx) doesn't spam test output with a real stderr logger.
+func CtxForTest() context.Context {
+	return logger.WithLogger(context.Background(), logger.NewLogger(ioutil.Discard))
+}
@@ -1,10 +1,14 @@
 package testutils
 
 import (
+	"archive/tar"
+	"bytes"
 	"context"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"testing"
 
 	"github.com/windmilleng/wmclient/pkg/os/temp"
@@ -68,6 +72,140 @@ func (f *TempDirFixture) WriteFile(path string, contents string) {
 	}
 }
 
+// WriteTree materializes a whole tree of files by packing them into an
+// in-memory tar archive and unpacking it with WriteTarball. Files are
+// written in sorted path order. It does not batch syscalls: WriteTarball
+// still calls MkdirAll and WriteFile once per file, as WriteFile does.
+func (f *TempDirFixture) WriteTree(files map[string]string) {
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		contents := files[p]
+		err := tw.WriteHeader(&tar.Header{
+			Name: p,
+			Mode: 0777,
+			Size: int64(len(contents)),
+		})
+		if err != nil {
+			f.t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(contents)); err != nil {
+			f.t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		f.t.Fatal(err)
+	}
+
+	f.WriteTarball(buf)
+}
+
+// WriteTarball unpacks the tar stream r into the fixture's directory.
+func (f *TempDirFixture) WriteTarball(r io.Reader) {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			f.t.Fatal(err)
+		}
+
+		fullPath := filepath.Join(f.Path(), header.Name)
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(fullPath, os.FileMode(0777)); err != nil {
+				f.t.Fatal(err)
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(fullPath), os.FileMode(0777)); err != nil {
+				f.t.Fatal(err)
+			}
+			contents, err := ioutil.ReadAll(tr)
+			if err != nil {
+				f.t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(fullPath, contents, os.FileMode(0777)); err != nil {
+				f.t.Fatal(err)
+			}
+		}
+	}
+}
+
+// Snapshot tars up the fixture's current directory contents into memory, so
+// table-driven tests can cheaply Restore() between cases instead of
+// recreating a fresh fixture per case.
+func (f *TempDirFixture) Snapshot() []byte {
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+
+	err := filepath.Walk(f.Path(), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == f.Path() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(f.Path(), path)
+		if err != nil {
+			return err
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(relPath)
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		_, err = tw.Write(contents)
+		return err
+	})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		f.t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// Restore wipes the fixture's current directory contents and replaces them
+// with the contents of a snapshot previously taken with Snapshot().
+func (f *TempDirFixture) Restore(snapshot []byte) {
+	entries, err := ioutil.ReadDir(f.Path())
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	for _, e := range entries {
+		if err := os.RemoveAll(filepath.Join(f.Path(), e.Name())); err != nil {
+			f.t.Fatal(err)
+		}
+	}
+
+	f.WriteTarball(bytes.NewReader(snapshot))
+}
+
 func (f *TempDirFixture) TouchFiles(paths []string) {
 	for _, p := range paths {
 		f.WriteFile(p, "")
@@ -0,0 +1,47 @@
+package testutils
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshotRestoreRoundTrip(t *testing.T) {
+	f := NewTempDirFixture(t)
+	defer f.TearDown()
+
+	f.WriteTree(map[string]string{
+		"a.txt":          "original a",
+		"sub/b.txt":      "original b",
+		"sub/sub2/c.txt": "original c",
+	})
+
+	snapshot := f.Snapshot()
+
+	// Mutate the tree after the snapshot: overwrite a file, add a new one,
+	// and delete one entirely.
+	f.WriteFile("a.txt", "mutated a")
+	f.WriteFile("new.txt", "should not survive Restore")
+	f.Rm(filepath.Join("sub", "b.txt"))
+
+	f.Restore(snapshot)
+
+	assertFileContents(t, f, "a.txt", "original a")
+	assertFileContents(t, f, filepath.Join("sub", "b.txt"), "original b")
+	assertFileContents(t, f, filepath.Join("sub", "sub2", "c.txt"), "original c")
+
+	if _, err := ioutil.ReadFile(f.JoinPath("new.txt")); err == nil {
+		t.Errorf("expected new.txt (written after the snapshot) to be gone post-Restore")
+	}
+}
+
+func assertFileContents(t *testing.T, f *TempDirFixture, path string, want string) {
+	t.Helper()
+	contents, err := ioutil.ReadFile(f.JoinPath(path))
+	if err != nil {
+		t.Fatalf("reading %s: %v", path, err)
+	}
+	if string(contents) != want {
+		t.Errorf("%s = %q, want %q", path, string(contents), want)
+	}
+}
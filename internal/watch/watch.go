@@ -0,0 +1,104 @@
+package watch
+
+import (
+	"os"
+	"time"
+)
+
+// pollInterval is how often a Notify checks watched paths for changes. Real
+// filesystem-event-backed watchers shouldn't need this, but keeps this
+// package dependency-free (no vendored fsnotify) for now.
+const pollInterval = 100 * time.Millisecond
+
+// Event is a single change notification for a watched path.
+type Event interface {
+	Path() string
+}
+
+type fileEvent struct{ path string }
+
+func (e fileEvent) Path() string { return e.path }
+
+// Notify watches a set of paths for changes and reports them on Events().
+type Notify interface {
+	Start() error
+	Close() error
+	Events() <-chan Event
+	Errors() <-chan error
+}
+
+// NewWatcher builds the default, polling-based Notify for the given paths,
+// skipping anything in ignore.
+func NewWatcher(paths []string, ignore []string) (Notify, error) {
+	ig := make(map[string]bool, len(ignore))
+	for _, p := range ignore {
+		ig[p] = true
+	}
+	return &pollingNotify{
+		paths:   paths,
+		ignore:  ig,
+		eventCh: make(chan Event),
+		errCh:   make(chan error),
+		doneCh:  make(chan struct{}),
+		mtimes:  make(map[string]time.Time),
+	}, nil
+}
+
+type pollingNotify struct {
+	paths  []string
+	ignore map[string]bool
+
+	eventCh chan Event
+	errCh   chan error
+	doneCh  chan struct{}
+
+	mtimes map[string]time.Time
+}
+
+func (w *pollingNotify) Start() error {
+	w.scan(false)
+	go w.loop()
+	return nil
+}
+
+func (w *pollingNotify) loop() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.doneCh:
+			return
+		case <-ticker.C:
+			w.scan(true)
+		}
+	}
+}
+
+func (w *pollingNotify) scan(emit bool) {
+	for _, path := range w.paths {
+		if w.ignore[path] {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		prev, seen := w.mtimes[path]
+		w.mtimes[path] = info.ModTime()
+		if emit && (!seen || !prev.Equal(info.ModTime())) {
+			select {
+			case w.eventCh <- fileEvent{path: path}:
+			case <-w.doneCh:
+				return
+			}
+		}
+	}
+}
+
+func (w *pollingNotify) Events() <-chan Event { return w.eventCh }
+func (w *pollingNotify) Errors() <-chan error { return w.errCh }
+
+func (w *pollingNotify) Close() error {
+	close(w.doneCh)
+	return nil
+}